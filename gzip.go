@@ -3,12 +3,14 @@ package json
 import (
 	"bufio"
 	"compress/gzip"
+	"fmt"
 	"io"
 	"sync"
 )
 
 var bufferPool sync.Pool
 var gzipPool sync.Pool
+var gzipReaderPool sync.Pool
 
 // NewGZipWriter returns a writer implementation that emits the JSON in gzipped
 // form.
@@ -49,3 +51,117 @@ func (gjw *gzipWriter) Close() {
 	bufferPool.Put(gjw.bw)
 	gjw.bw = nil
 }
+
+// NewGZipReader returns a Reader that transparently decompresses gzipped
+// JSON input from r.  The underlying gzip.Reader is closed automatically
+// when the returned Reader's Read or ReadStream method finishes, whether it
+// succeeds or fails.
+func NewGZipReader(r io.Reader) (*Reader, error) {
+	var (
+		gr  *gzip.Reader
+		err error
+	)
+	if gri := gzipReaderPool.Get(); gri != nil {
+		gr = gri.(*gzip.Reader)
+		err = gr.Reset(r)
+	} else {
+		gr, err = gzip.NewReader(r)
+	}
+	if err != nil {
+		return nil, err
+	}
+	jr := NewReader(gr)
+	jr.closer = &pooledGZipReader{gr}
+	return jr, nil
+}
+
+// pooledGZipReader returns its gzip.Reader to gzipReaderPool once closed, so
+// that Reader itself doesn't need to know about the pooling.
+type pooledGZipReader struct{ gr *gzip.Reader }
+
+func (p *pooledGZipReader) Close() error {
+	err := p.gr.Close()
+	gzipReaderPool.Put(p.gr)
+	return err
+}
+
+// A CodecReader constructs a decompressing io.ReadCloser wrapping the given
+// stream, for use with RegisterCodec.
+type CodecReader func(io.Reader) (io.ReadCloser, error)
+
+// A CodecWriter constructs a compressing io.WriteCloser wrapping the given
+// stream, for use with RegisterCodec.
+type CodecWriter func(io.Writer) (io.WriteCloser, error)
+
+type codec struct {
+	newReader CodecReader
+	newWriter CodecWriter
+}
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[string]codec{
+		"gzip": {
+			newReader: func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) },
+			newWriter: func(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriter(w), nil },
+		},
+	}
+)
+
+// RegisterCodec registers a named compression codec, such as "zstd" or
+// "lz4", so that NewCodecReader and NewCodecWriter can use it without this
+// module needing to import the codec's package itself.  "gzip" is
+// registered by default; calling RegisterCodec("gzip", ...) replaces it.
+func RegisterCodec(name string, newReader CodecReader, newWriter CodecWriter) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	codecs[name] = codec{newReader, newWriter}
+}
+
+// NewCodecReader returns a Reader that transparently decompresses input
+// using the named codec, which must already have been registered with
+// RegisterCodec (or be "gzip", which is registered by default).
+func NewCodecReader(name string, r io.Reader) (*Reader, error) {
+	codecsMu.RLock()
+	c, ok := codecs[name]
+	codecsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("json: unregistered codec %q", name)
+	}
+	rc, err := c.newReader(r)
+	if err != nil {
+		return nil, err
+	}
+	jr := NewReader(rc)
+	jr.closer = rc
+	return jr, nil
+}
+
+// NewCodecWriter returns a Writer that transparently compresses output
+// using the named codec, which must already have been registered with
+// RegisterCodec (or be "gzip", which is registered by default).
+func NewCodecWriter(name string, w io.Writer) (Writer, error) {
+	codecsMu.RLock()
+	c, ok := codecs[name]
+	codecsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("json: unregistered codec %q", name)
+	}
+	wc, err := c.newWriter(w)
+	if err != nil {
+		return nil, err
+	}
+	return &codecWriter{Writer: NewWriter(wc), wc: wc}, nil
+}
+
+// codecWriter closes the underlying compressor after the base Writer is
+// closed, mirroring gzipWriter's role for the pooled gzip implementation.
+type codecWriter struct {
+	Writer
+	wc io.WriteCloser
+}
+
+func (cw *codecWriter) Close() {
+	cw.Writer.Close()
+	cw.wc.Close()
+}
@@ -0,0 +1,63 @@
+package json
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestGZipRoundTrip verifies that a document written with NewGZipWriter can
+// be read back with NewGZipReader, and that the Reader closes the
+// decompressor without error.
+func TestGZipRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	gw := NewGZipWriter(&buf)
+	gw.String("hello")
+	gw.Close()
+
+	gr, err := NewGZipReader(&buf)
+	if err != nil {
+		t.Fatalf("NewGZipReader returned error: %v", err)
+	}
+	var got string
+	if err := gr.Read(StringHandler(func(s string) { got = s })); err != nil {
+		t.Fatalf("Read returned error: %v", err)
+	}
+	if got != "hello" {
+		t.Fatalf("got %q, want %q", got, "hello")
+	}
+}
+
+// TestCodecRoundTrip verifies that NewCodecWriter/NewCodecReader round-trip
+// a document through the default "gzip" codec.
+func TestCodecRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	cw, err := NewCodecWriter("gzip", &buf)
+	if err != nil {
+		t.Fatalf("NewCodecWriter returned error: %v", err)
+	}
+	cw.Int(42)
+	cw.Close()
+
+	cr, err := NewCodecReader("gzip", &buf)
+	if err != nil {
+		t.Fatalf("NewCodecReader returned error: %v", err)
+	}
+	var got int
+	if err := cr.Read(IntHandler(func(i int) { got = i })); err != nil {
+		t.Fatalf("Read returned error: %v", err)
+	}
+	if got != 42 {
+		t.Fatalf("got %d, want 42", got)
+	}
+}
+
+// TestCodecUnregistered verifies that NewCodecReader/NewCodecWriter reject
+// an unknown codec name instead of panicking.
+func TestCodecUnregistered(t *testing.T) {
+	if _, err := NewCodecReader("zstd", bytes.NewReader(nil)); err == nil {
+		t.Fatal("expected an error for an unregistered codec, got nil")
+	}
+	if _, err := NewCodecWriter("zstd", &bytes.Buffer{}); err == nil {
+		t.Fatal("expected an error for an unregistered codec, got nil")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // A Handlers structure defines how parsed JSON is handled.  Any field which is
@@ -17,11 +18,23 @@ type Handlers struct {
 	Ignore bool
 	// Null is called when a JSON null is encountered.
 	Null func()
+	// Number is called with the untouched textual form of a JSON number,
+	// when the caller needs precision that strconv.Atoi / ParseFloat
+	// cannot preserve (large IDs, monetary values, scientific data).  It
+	// takes priority over Int, Int64, Uint64, and Float when set.
+	Number func(raw string)
 	// Int is called when a JSON number without a fractional part is
 	// encountered.
 	Int func(int)
-	// Float is called when a JSON number is encountered (unless Int is
-	// non-nil and the number has no fractional part).
+	// Int64 is called when a JSON number without a fractional part is
+	// encountered (unless Int is non-nil and can hold it), avoiding the
+	// truncation risk of Int on 32-bit platforms.
+	Int64 func(int64)
+	// Uint64 is called when a JSON number without a fractional part is
+	// encountered (unless Int or Int64 is non-nil and can hold it).
+	Uint64 func(uint64)
+	// Float is called when a JSON number is encountered (unless Int,
+	// Int64, or Uint64 is non-nil and can hold it as an integer).
 	Float func(float64)
 	// String is called when a JSON string is encountered (unless Time is
 	// non-nil and the string looks like an RFC3339 timestamp).
@@ -39,7 +52,8 @@ type Handlers struct {
 }
 
 func (h Handlers) empty() bool {
-	return !h.Ignore && h.Null == nil && h.Int == nil && h.Float == nil &&
+	return !h.Ignore && h.Null == nil && h.Number == nil && h.Int == nil &&
+		h.Int64 == nil && h.Uint64 == nil && h.Float == nil &&
 		h.String == nil && h.Time == nil && h.Bool == nil &&
 		h.Object == nil && h.Array == nil
 }
@@ -55,6 +69,20 @@ type Reader struct {
 	line int
 	col  int
 	err  error
+	// invalidUTF8 records whether the most recent readRune call had to
+	// substitute utf8.RuneError for a malformed encoding in the input.
+	invalidUTF8 bool
+	// closer, if non-nil, is closed when Read or ReadStream finishes (on
+	// success or on error).  NewGZipReader and NewCodecReader set this to
+	// the decompressor they create.
+	closer io.Closer
+	// StrictUnicode, when set, causes the Reader to raise an error on
+	// invalid UTF-8 in the input, on lone or mis-paired UTF-16 surrogate
+	// escapes, and on the unescaped DEL character (0x7F) in strings.
+	// When unset (the default), these cases are tolerated: invalid UTF-8
+	// and lone surrogates decode to utf8.RuneError, mirroring the Writer's
+	// handling of invalid input, and DEL is kept as is.
+	StrictUnicode bool
 }
 
 // Raise raises an error in the reader, causing its Read method to return the
@@ -65,6 +93,17 @@ func (h *Reader) Raise(err string) {
 	}
 }
 
+// closeStream closes h.closer (the decompressor, if any, wrapping the
+// underlying stream) and reflects any resulting error into *result,
+// preferring whatever error Read or ReadStream already produced.
+func (h *Reader) closeStream(result *error) {
+	if cerr := h.closer.Close(); cerr != nil && h.err == nil {
+		h.err = cerr
+	}
+	h.closer = nil
+	*result = h.err
+}
+
 // Read reads the input stream until EOF, and uses the supplied handlers to
 // parse it.  It returns an error if it hits a JSON syntax error, if it hits a
 // JSON element for which no handler was provided, or if a handler called Raise.
@@ -72,6 +111,9 @@ func (h *Reader) Read(handlers Handlers) (err error) {
 	var (
 		r rune
 	)
+	if h.closer != nil {
+		defer h.closeStream(&err)
+	}
 	h.parseOne(handlers)
 	if h.err != nil {
 		return h.err
@@ -88,17 +130,46 @@ func (h *Reader) Read(handlers Handlers) (err error) {
 	return h.err
 }
 
+// ReadStream reads the input stream until EOF, treating it as a sequence of
+// whitespace-separated JSON values (the JSONL / ndjson / concatenated-JSON
+// convention), and invokes the supplied handlers for each value in turn.  It
+// returns an error if it hits a JSON syntax error, if it hits a JSON element
+// for which no handler was provided, or if a handler called Raise.
+func (h *Reader) ReadStream(handlers Handlers) (err error) {
+	if h.closer != nil {
+		defer h.closeStream(&err)
+	}
+	for {
+		h.skipWhitespace()
+		if r := h.readRune(true); r == 0 {
+			return h.err
+		}
+		h.unreadRune()
+		h.parseOne(handlers)
+		if h.err != nil {
+			return h.err
+		}
+	}
+}
+
 func (h *Reader) readRune(allowEOF bool) (r rune) {
-	var err error
+	var (
+		size int
+		err  error
+	)
 
-	r, _, err = h.r.ReadRune()
+	r, size, err = h.r.ReadRune()
 	if err == io.EOF && allowEOF {
 		return 0
 	}
 	if err != nil {
-		h.err = err
+		// Annotate with the current position, same as a JSON syntax
+		// error, so a decompression failure midstream (e.g. truncated
+		// gzip input) is as easy to locate as a parse error.
+		h.Raise(err.Error())
 		return 0
 	}
+	h.invalidUTF8 = r == utf8.RuneError && size == 1
 	if r == '\n' {
 		h.line++
 		h.col = 1
@@ -115,10 +186,12 @@ func (h *Reader) unreadRune() {
 
 func (h *Reader) skipWhitespace() {
 	for {
-		switch r := h.readRune(false); r {
+		switch r := h.readRune(true); r {
 		case 0:
 			return
 		case ' ', '\t', '\r', '\n':
+			continue
+		default:
 			h.unreadRune()
 			return
 		}
@@ -256,17 +329,46 @@ func (h *Reader) parseNumber(handlers Handlers) {
 	if h.err != nil || handlers.Ignore {
 		return
 	}
+	s := string(num)
+	if !validJSONNumber(s) {
+		h.Raise("invalid JSON number")
+		return
+	}
+	if handlers.Number != nil {
+		handlers.Number(s)
+		return
+	}
+	haveFallback := handlers.Int64 != nil || handlers.Uint64 != nil || handlers.Float != nil
 	if handlers.Int != nil {
-		if i, err := strconv.Atoi(string(num)); err == nil {
+		if i, err := strconv.Atoi(s); err == nil {
 			handlers.Int(i)
 			return
+		} else if !haveFallback {
+			h.Raise("JSON number is not an integer")
+			return
+		}
+	}
+	haveFallback = handlers.Uint64 != nil || handlers.Float != nil
+	if handlers.Int64 != nil {
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			handlers.Int64(i)
+			return
+		} else if !haveFallback {
+			h.Raise("JSON number is not an integer")
+			return
+		}
+	}
+	if handlers.Uint64 != nil {
+		if u, err := strconv.ParseUint(s, 10, 64); err == nil {
+			handlers.Uint64(u)
+			return
 		} else if handlers.Float == nil {
 			h.Raise("JSON number is not an integer")
 			return
 		}
 	}
 	if handlers.Float != nil {
-		if f, err := strconv.ParseFloat(string(num), 64); err == nil {
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
 			handlers.Float(f)
 			return
 		}
@@ -276,6 +378,50 @@ func (h *Reader) parseNumber(handlers Handlers) {
 	h.Raise("unexpected number in JSON")
 }
 
+// validJSONNumber reports whether s is a syntactically valid JSON number,
+// per the grammar in RFC 8259 section 6 (no leading zeros other than a bare
+// "0", a digit required after "." and after "e"/"E", etc.).
+func validJSONNumber(s string) bool {
+	i, n := 0, len(s)
+	if i < n && s[i] == '-' {
+		i++
+	}
+	switch {
+	case i >= n:
+		return false
+	case s[i] == '0':
+		i++
+	case s[i] >= '1' && s[i] <= '9':
+		for i < n && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+	default:
+		return false
+	}
+	if i < n && s[i] == '.' {
+		i++
+		if i >= n || s[i] < '0' || s[i] > '9' {
+			return false
+		}
+		for i < n && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+	}
+	if i < n && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		if i < n && (s[i] == '+' || s[i] == '-') {
+			i++
+		}
+		if i >= n || s[i] < '0' || s[i] > '9' {
+			return false
+		}
+		for i < n && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+	}
+	return i == n
+}
+
 func (h *Reader) parseKeyword(handlers Handlers) {
 	var (
 		buf [5]byte
@@ -327,12 +473,49 @@ func (h *Reader) parseKeyword(handlers Handlers) {
 	h.Raise("unquoted string in JSON")
 }
 
+// readUnicodeEscape reads the four hex digits of a \uXXXX escape, the \u of
+// which has already been consumed, and returns the resulting code point
+// (which may be a surrogate half).
+func (h *Reader) readUnicodeEscape() (r rune, ok bool) {
+	var u [4]byte
+
+	if n, err := h.r.Read(u[:]); err != nil {
+		h.err = err
+		return 0, false
+	} else if n != 4 {
+		h.Raise("invalid Unicode escape in JSON string")
+		return 0, false
+	}
+	h.col += 4
+	i, err := strconv.ParseInt(string(u[:]), 16, 32)
+	if err != nil {
+		h.Raise("invalid Unicode escape in JSON string")
+		return 0, false
+	}
+	return rune(i), true
+}
+
+// peekLowSurrogate looks for a low surrogate escape ("\uDC00" through
+// "\uDFFF") immediately ahead in the input, without consuming it unless it matches.
+func (h *Reader) peekLowSurrogate() (lo rune, ok bool) {
+	peek, _ := h.r.Peek(6)
+	if len(peek) < 6 || peek[0] != '\\' || peek[1] != 'u' {
+		return 0, false
+	}
+	v, err := strconv.ParseInt(string(peek[2:6]), 16, 32)
+	if err != nil || v < 0xDC00 || v > 0xDFFF {
+		return 0, false
+	}
+	h.r.Discard(6)
+	h.col += 6
+	return rune(v), true
+}
+
 func (h *Reader) parseString(handlers Handlers) {
 	var (
 		sb strings.Builder
 		s  string
 		r  rune
-		u  [4]byte
 	)
 	for {
 		if r = h.readRune(false); r == 0 {
@@ -341,11 +524,19 @@ func (h *Reader) parseString(handlers Handlers) {
 		if r == '"' {
 			break
 		}
-		if r < 32 {
+		if r < 32 || (r == 0x7F && h.StrictUnicode) {
 			h.Raise("unexpected control character in JSON string")
 			return
 		}
 		if r != '\\' {
+			if h.invalidUTF8 {
+				if h.StrictUnicode {
+					h.Raise("invalid UTF-8 in JSON string")
+					return
+				}
+				sb.WriteRune(utf8.RuneError)
+				continue
+			}
 			sb.WriteRune(r)
 			continue
 		}
@@ -366,25 +557,42 @@ func (h *Reader) parseString(handlers Handlers) {
 		case 't':
 			sb.WriteByte('\t')
 		case 'u':
-			if n, err := h.r.Read(u[:]); err != nil {
-				h.err = err
-				return
-			} else if n != 4 {
-				h.Raise("invalid Unicode escape in JSON string")
+			hi, ok := h.readUnicodeEscape()
+			if !ok {
 				return
 			}
-			h.col += 4
-			if i, err := strconv.ParseInt(string(u[:]), 16, 32); err != nil {
-				h.Raise("invalid Unicode escape in JSON string")
-				return
-			} else {
-				sb.WriteRune(rune(i))
+			switch {
+			case hi < 0xD800 || hi > 0xDFFF:
+				// An ordinary code point.
+				sb.WriteRune(hi)
+			case hi <= 0xDBFF:
+				// A high surrogate: it must be followed immediately by
+				// a low surrogate escape, with which it combines into
+				// one code point outside the Basic Multilingual Plane.
+				if lo, ok := h.peekLowSurrogate(); ok {
+					sb.WriteRune(0x10000 + (hi-0xD800)*0x400 + (lo - 0xDC00))
+				} else if h.StrictUnicode {
+					h.Raise("unpaired surrogate in JSON string")
+					return
+				} else {
+					sb.WriteRune(utf8.RuneError)
+				}
+			default:
+				// A low surrogate with no preceding high surrogate.
+				if h.StrictUnicode {
+					h.Raise("lone surrogate in JSON string")
+					return
+				}
+				sb.WriteRune(utf8.RuneError)
 			}
 		default:
 			h.Raise("unexpected escape sequence in JSON string")
 			return
 		}
 	}
+	if handlers.Ignore {
+		return
+	}
 	s = sb.String()
 	if handlers.Time != nil {
 		if t, err := time.Parse(time.RFC3339, s); err == nil {
@@ -406,7 +614,10 @@ func (h *Reader) parseString(handlers Handlers) {
 func RejectHandler() Handlers                        { return Handlers{} }
 func IgnoreHandler() Handlers                        { return Handlers{Ignore: true} }
 func NullHandler(f func()) Handlers                  { return Handlers{Null: f} }
+func RawNumberHandler(f func(string)) Handlers       { return Handlers{Number: f} }
 func IntHandler(f func(int)) Handlers                { return Handlers{Int: f} }
+func Int64Handler(f func(int64)) Handlers            { return Handlers{Int64: f} }
+func Uint64Handler(f func(uint64)) Handlers          { return Handlers{Uint64: f} }
 func FloatHandler(f func(float64)) Handlers          { return Handlers{Float: f} }
 func StringHandler(f func(string)) Handlers          { return Handlers{String: f} }
 func TimeHandler(f func(time.Time)) Handlers         { return Handlers{Time: f} }
@@ -414,8 +625,14 @@ func BoolHandler(f func(bool)) Handlers              { return Handlers{Bool: f}
 func ObjectHandler(f func(string) Handlers) Handlers { return Handlers{Object: f} }
 func ArrayHandler(f func() Handlers) Handlers        { return Handlers{Array: f} }
 func IntNullHandler(f func(int)) Handlers            { return Handlers{Int: f, Null: func() { f(0) }} }
-func FloatNullHandler(f func(float64)) Handlers      { return Handlers{Float: f, Null: func() { f(0.0) }} }
-func StringNullHandler(f func(string)) Handlers      { return Handlers{String: f, Null: func() { f("") }} }
+func Int64NullHandler(f func(int64)) Handlers {
+	return Handlers{Int64: f, Null: func() { f(0) }}
+}
+func Uint64NullHandler(f func(uint64)) Handlers {
+	return Handlers{Uint64: f, Null: func() { f(0) }}
+}
+func FloatNullHandler(f func(float64)) Handlers { return Handlers{Float: f, Null: func() { f(0.0) }} }
+func StringNullHandler(f func(string)) Handlers { return Handlers{String: f, Null: func() { f("") }} }
 func TimeNullHandler(f func(time.Time)) Handlers {
 	return Handlers{Time: f, Null: func() { f(time.Time{}) }}
 }
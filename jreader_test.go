@@ -0,0 +1,152 @@
+package json
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestReadCompact verifies that Read correctly parses JSON with no leading
+// or interior whitespace, where skipWhitespace finds nothing to skip and must
+// leave the next rune unread.
+func TestReadCompact(t *testing.T) {
+	var got int
+	err := NewReader(strings.NewReader(`{"a":1}`)).Read(ObjectHandler(func(key string) Handlers {
+		if key == "a" {
+			return IntHandler(func(i int) { got = i })
+		}
+		return RejectHandler()
+	}))
+	if err != nil {
+		t.Fatalf("Read returned error: %v", err)
+	}
+	if got != 1 {
+		t.Fatalf("got %d, want 1", got)
+	}
+}
+
+// TestReadStream verifies that ReadStream parses a sequence of
+// whitespace-separated top-level values and returns nil at a clean EOF
+// between values, rather than latching an "EOF" error.
+func TestReadStream(t *testing.T) {
+	var got []int
+	err := NewReader(strings.NewReader("1 2\n3")).ReadStream(IntHandler(func(i int) {
+		got = append(got, i)
+	}))
+	if err != nil {
+		t.Fatalf("ReadStream returned error: %v", err)
+	}
+	if len(got) != 3 || got[0] != 1 || got[1] != 2 || got[2] != 3 {
+		t.Fatalf("got %v, want [1 2 3]", got)
+	}
+}
+
+// TestReadStreamSingleValue verifies that ReadStream succeeds (rather than
+// reporting an EOF error) when the stream contains exactly one value and no
+// trailing whitespace.
+func TestReadStreamSingleValue(t *testing.T) {
+	var got int
+	err := NewReader(strings.NewReader("1")).ReadStream(IntHandler(func(i int) { got = i }))
+	if err != nil {
+		t.Fatalf("ReadStream returned error: %v", err)
+	}
+	if got != 1 {
+		t.Fatalf("got %d, want 1", got)
+	}
+}
+
+// TestNumberHandlers verifies that Number receives the untouched textual
+// form of a JSON number, and that Int64/Uint64 parse numbers too large for
+// Int without truncation.
+func TestNumberHandlers(t *testing.T) {
+	var raw string
+	err := NewReader(strings.NewReader(`12345678901234567890.5`)).Read(RawNumberHandler(func(s string) { raw = s }))
+	if err != nil {
+		t.Fatalf("Read returned error: %v", err)
+	}
+	if raw != "12345678901234567890.5" {
+		t.Fatalf("got %q, want %q", raw, "12345678901234567890.5")
+	}
+
+	var i64 int64
+	err = NewReader(strings.NewReader(`-9223372036854775808`)).Read(Int64Handler(func(i int64) { i64 = i }))
+	if err != nil {
+		t.Fatalf("Read returned error: %v", err)
+	}
+	if i64 != -9223372036854775808 {
+		t.Fatalf("got %d, want -9223372036854775808", i64)
+	}
+
+	var u64 uint64
+	err = NewReader(strings.NewReader(`18446744073709551615`)).Read(Uint64Handler(func(u uint64) { u64 = u }))
+	if err != nil {
+		t.Fatalf("Read returned error: %v", err)
+	}
+	if u64 != 18446744073709551615 {
+		t.Fatalf("got %d, want 18446744073709551615", u64)
+	}
+}
+
+// TestInvalidNumberGrammar verifies that parseNumber rejects tokens that
+// merely contain valid number characters but don't match the JSON number
+// grammar, such as a leading zero followed by more digits.
+func TestInvalidNumberGrammar(t *testing.T) {
+	err := NewReader(strings.NewReader(`01`)).Read(IntHandler(func(int) {}))
+	if err == nil {
+		t.Fatal("expected an error for a leading-zero number, got nil")
+	}
+}
+
+// TestSurrogatePair verifies that a \uD834\uDD1E escape pair (the
+// high/low surrogates for U+1D11E, the musical G clef) decodes to a single
+// correct rune via readUnicodeEscape/peekLowSurrogate, rather than two
+// invalid ones.
+func TestSurrogatePair(t *testing.T) {
+	var got string
+	err := NewReader(strings.NewReader(`"\uD834\uDD1E"`)).Read(StringHandler(func(s string) { got = s }))
+	if err != nil {
+		t.Fatalf("Read returned error: %v", err)
+	}
+	if got != "\U0001D11E" {
+		t.Fatalf("got %q, want %q", got, "\U0001D11E")
+	}
+}
+
+// TestLoneSurrogate verifies that an unpaired surrogate escape decodes to
+// utf8.RuneError by default, and is rejected when StrictUnicode is set.
+func TestLoneSurrogate(t *testing.T) {
+	var got string
+	err := NewReader(strings.NewReader(`"\uD834x"`)).Read(StringHandler(func(s string) { got = s }))
+	if err != nil {
+		t.Fatalf("Read returned error: %v", err)
+	}
+	if got != "�x" {
+		t.Fatalf("got %q, want %q", got, "�x")
+	}
+
+	r := NewReader(strings.NewReader(`"\uD834x"`))
+	r.StrictUnicode = true
+	if err := r.Read(StringHandler(func(string) {})); err == nil {
+		t.Fatal("expected an error for a lone surrogate in strict mode, got nil")
+	}
+}
+
+// TestInvalidUTF8 verifies that malformed UTF-8 in the raw input decodes to
+// utf8.RuneError by default, and is rejected when StrictUnicode is set.
+func TestInvalidUTF8(t *testing.T) {
+	input := "\"a\xffb\""
+
+	var got string
+	err := NewReader(strings.NewReader(input)).Read(StringHandler(func(s string) { got = s }))
+	if err != nil {
+		t.Fatalf("Read returned error: %v", err)
+	}
+	if got != "a�b" {
+		t.Fatalf("got %q, want %q", got, "a�b")
+	}
+
+	r := NewReader(strings.NewReader(input))
+	r.StrictUnicode = true
+	if err := r.Read(StringHandler(func(string) {})); err == nil {
+		t.Fatal("expected an error for invalid UTF-8 in strict mode, got nil")
+	}
+}
@@ -0,0 +1,107 @@
+package json
+
+import "strings"
+
+// A Schema builds a Handlers tree for a nested JSON document by registering
+// handlers at JSON-pointer-like paths, instead of requiring the caller to
+// hand-write a pyramid of Object/Array closures for every level.  This is
+// useful for selecting a few fields out of a large document, the same case
+// streaming decoders like jstream target.
+//
+// A path is a sequence of "/"- or "."-separated segments.  A segment of "*"
+// matches any element of an array; a segment of "**" matches any key of an
+// object.  For example, At("items/*/id", h) wires h to the "id" property of
+// every element of the "items" array.
+type Schema struct {
+	root    schemaNode
+	lenient bool
+}
+
+// NewSchema returns an empty Schema.
+func NewSchema() *Schema {
+	return &Schema{}
+}
+
+// Lenient causes Build to wire IgnoreHandler() for any object key or array
+// that At was not told about, instead of rejecting it.  Without Lenient, an
+// unmatched key or array is a JSON error, the same as it would be if the
+// caller had hand-written the Handlers and left it out.
+func (s *Schema) Lenient() *Schema {
+	s.lenient = true
+	return s
+}
+
+// At registers the handlers to use for the value found at path, and returns
+// s so that calls can be chained.
+func (s *Schema) At(path string, h Handlers) *Schema {
+	node := &s.root
+	for _, seg := range splitSchemaPath(path) {
+		node = node.child(seg)
+	}
+	node.handlers = h
+	return s
+}
+
+// Build synthesizes the nested Object and Array handlers needed to route
+// each path registered with At to its handlers, and returns the result as
+// the Handlers for the root of the document.
+func (s *Schema) Build() Handlers {
+	return s.root.build(s.lenient)
+}
+
+func splitSchemaPath(path string) []string {
+	return strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '.' })
+}
+
+// schemaNode is one segment of the path tree built by Schema.At.
+type schemaNode struct {
+	handlers Handlers
+	children map[string]*schemaNode
+}
+
+// child returns the child node for the given path segment, creating it if
+// necessary.
+func (n *schemaNode) child(seg string) *schemaNode {
+	if n.children == nil {
+		n.children = make(map[string]*schemaNode)
+	}
+	c, ok := n.children[seg]
+	if !ok {
+		c = &schemaNode{}
+		n.children[seg] = c
+	}
+	return c
+}
+
+// build returns the handlers registered directly at this node (if any), with
+// Object and Array filled in to route to this node's children (if any)
+// wherever the registered handlers leave them nil.  A registered Object or
+// Array takes priority over routing to the children.
+func (n *schemaNode) build(lenient bool) Handlers {
+	h := n.handlers
+	if len(n.children) == 0 {
+		return h
+	}
+	if h.Object == nil {
+		h.Object = func(key string) Handlers {
+			if c, ok := n.children[key]; ok {
+				return c.build(lenient)
+			}
+			if c, ok := n.children["**"]; ok {
+				return c.build(lenient)
+			}
+			if lenient {
+				return IgnoreHandler()
+			}
+			return Handlers{}
+		}
+	}
+	if h.Array == nil {
+		if c, ok := n.children["*"]; ok {
+			h.Array = func() Handlers { return c.build(lenient) }
+		} else if lenient {
+			h.Array = func() Handlers { return IgnoreHandler() }
+		}
+	}
+	return h
+}
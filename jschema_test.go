@@ -0,0 +1,57 @@
+package json
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestSchema verifies that Schema routes nested object paths and an array
+// wildcard ("*") to their registered handlers, and that Lenient ignores
+// keys that weren't registered with At, both in objects and in array
+// elements.
+func TestSchema(t *testing.T) {
+	var street string
+	var ids []int
+	schema := NewSchema().
+		Lenient().
+		At("user/address/street", StringHandler(func(s string) { street = s })).
+		At("items/*/id", IntHandler(func(i int) { ids = append(ids, i) }))
+
+	input := `{"user":{"address":{"street":"Main St","zip":"00000"}},` +
+		`"items":[{"id":1,"x":"y"},{"id":2}],"other":"ignored"}`
+	err := NewReader(strings.NewReader(input)).Read(schema.Build())
+	if err != nil {
+		t.Fatalf("Read returned error: %v", err)
+	}
+	if street != "Main St" {
+		t.Fatalf("street = %q, want %q", street, "Main St")
+	}
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+		t.Fatalf("ids = %v, want [1 2]", ids)
+	}
+}
+
+// TestSchemaWildcardKey verifies that "**" matches any object key at that
+// level.
+func TestSchemaWildcardKey(t *testing.T) {
+	var keys []string
+	schema := NewSchema().At("props/**", StringHandler(func(s string) { keys = append(keys, s) }))
+
+	err := NewReader(strings.NewReader(`{"props":{"a":"x","b":"y"}}`)).Read(schema.Build())
+	if err != nil {
+		t.Fatalf("Read returned error: %v", err)
+	}
+	if len(keys) != 2 || keys[0] != "x" || keys[1] != "y" {
+		t.Fatalf("keys = %v, want [x y]", keys)
+	}
+}
+
+// TestSchemaNotLenient verifies that, without Lenient, an unregistered key
+// is rejected rather than silently skipped.
+func TestSchemaNotLenient(t *testing.T) {
+	schema := NewSchema().At("a", IntHandler(func(int) {}))
+	err := NewReader(strings.NewReader(`{"a":1,"b":2}`)).Read(schema.Build())
+	if err == nil {
+		t.Fatal("expected an error for an unregistered key, got nil")
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"math"
+	"strconv"
 	"sync"
 	"unicode/utf8"
 )
@@ -27,39 +29,108 @@ var (
 
 var writerPool sync.Pool
 
+// A WriterOption configures a Writer created by NewWriter.
+type WriterOption func(*writer)
+
+// nonFiniteMode controls how a Writer handles a NaN or ±Inf float, none of
+// which are valid JSON values.
+type nonFiniteMode int
+
+const (
+	// nonFinitePanic, the default, panics when asked to encode a
+	// non-finite float.
+	nonFinitePanic nonFiniteMode = iota
+	nonFiniteNull
+	nonFiniteString
+)
+
+// WithNonFiniteNull causes a NaN or ±Inf float passed to Float64 or Float32
+// to be encoded as a JSON null, rather than the default of panicking.
+func WithNonFiniteNull() WriterOption {
+	return func(jw *writer) { jw.nonFinite = nonFiniteNull }
+}
+
+// WithNonFiniteString causes a NaN or ±Inf float passed to Float64 or
+// Float32 to be encoded as the given string, rather than the default of
+// panicking.
+func WithNonFiniteString(s string) WriterOption {
+	return func(jw *writer) {
+		jw.nonFinite = nonFiniteString
+		jw.nonFiniteStr = s
+	}
+}
+
 // NewWriter creates a new Writer for generating JSON output.
-func NewWriter(w io.Writer) Writer {
+func NewWriter(w io.Writer, opts ...WriterOption) Writer {
 	var jw *writer
 	if jwi := writerPool.Get(); jwi != nil {
 		jw = jwi.(*writer)
 		jw.w.Reset(w)
-		jw.comma = false
-		jw.inObject = false
+		jw.stack = append(jw.stack[:0], frame{array: true})
+		jw.nonFinite = nonFinitePanic
+		jw.nonFiniteStr = ""
 	} else {
-		jw = &writer{w: bufio.NewWriter(w)}
+		jw = &writer{w: bufio.NewWriter(w), stack: []frame{{array: true}}}
+	}
+	for _, opt := range opts {
+		opt(jw)
 	}
 	return jw
 }
 
 // Writer is a JSON writer.
+//
+// Object, Prop, and Array are the original callback-based API: they nest by
+// calling a supplied function, which forces the whole document to be built
+// within Go's call stack.  BeginObject, Key, EndObject, BeginArray, and
+// EndArray are a lower-level streaming API that maintains its own stack
+// instead, for documents whose rows are produced incrementally (e.g. from a
+// database cursor or channel).  The two APIs may be freely mixed; Object,
+// Prop, and Array are implemented as sugar on top of the streaming calls.
 type Writer interface {
 	Close()
 	Object(f func())
 	Prop(name string, value interface{})
 	Array(f func())
+	BeginObject()
+	EndObject()
+	BeginArray()
+	EndArray()
+	Key(name string)
 	Null()
 	String(s string)
 	Int(i int)
+	Int64(i int64)
+	Uint64(u uint64)
+	Float64(f float64)
+	Float32(f float32)
+	Number(raw string)
 	Bool(b bool)
 	Raw(s string)
 	RawByte(b byte)
 }
 
+// frame tracks the state of one open Object or Array, or (at the bottom of
+// the stack) the implicit root context in which top-level values are
+// written.
+type frame struct {
+	// array is true for an Array (or the root context), and false for an
+	// Object.
+	array bool
+	// comma is true once a token has been written to this frame, so that
+	// the next one needs a comma first.
+	comma bool
+	// needKey is true, within an Object frame, when the next token must
+	// be a Key rather than a value.
+	needKey bool
+}
+
 // writer is the base implementation of Writer.
 type writer struct {
-	w        *bufio.Writer
-	comma    bool
-	inObject bool
+	w            *bufio.Writer
+	stack        []frame
+	nonFinite    nonFiniteMode
+	nonFiniteStr string
 }
 
 // Close flushes the JSON output.  The Writer must not be used again after Close
@@ -69,36 +140,109 @@ func (jw *writer) Close() {
 	writerPool.Put(jw)
 }
 
-// Object writes an object to the JSON output.  The properties of the object are
-// given by Prop calls made in the supplied function.
-func (jw *writer) Object(f func()) {
-	if jw.inObject {
-		panic("Object can only contain Prop")
+// top returns the frame for the innermost currently open Object or Array (or
+// the root frame, if none is open).
+func (jw *writer) top() *frame {
+	return &jw.stack[len(jw.stack)-1]
+}
+
+// beforeValue writes a comma if needed, and panics if a value is not allowed
+// at this point (i.e., a Key is expected instead).
+func (jw *writer) beforeValue() {
+	top := jw.top()
+	if !top.array && top.needKey {
+		panic("expected a Key, not a value, in JSON object")
 	}
-	if jw.comma {
+	if top.comma {
 		jw.w.WriteByte(comma)
 	}
-	saveInObject := jw.inObject
-	jw.comma = false
-	jw.inObject = true
+}
+
+// afterValue records that a complete value was written to the current frame,
+// so that a comma is needed before the next token and, within an Object, a
+// Key is expected next.
+func (jw *writer) afterValue() {
+	top := jw.top()
+	top.comma = true
+	if !top.array {
+		top.needKey = true
+	}
+}
+
+// BeginObject begins a JSON object.  Each property must be written with Key
+// followed by exactly one value, and the object must be closed with
+// EndObject.
+func (jw *writer) BeginObject() {
+	jw.beforeValue()
 	jw.w.WriteByte(openBrace)
-	f()
+	jw.stack = append(jw.stack, frame{needKey: true})
+}
+
+// EndObject closes a JSON object begun with BeginObject.
+func (jw *writer) EndObject() {
+	top := jw.top()
+	if top.array {
+		panic("EndObject does not match BeginArray")
+	}
+	if !top.needKey {
+		panic("EndObject called with a Key but no value")
+	}
+	jw.stack = jw.stack[:len(jw.stack)-1]
 	jw.w.WriteByte(closeBrace)
-	jw.comma = true
-	jw.inObject = saveInObject
+	jw.afterValue()
+}
+
+// BeginArray begins a JSON array.  Its elements are written with the usual
+// value methods, and it must be closed with EndArray.
+func (jw *writer) BeginArray() {
+	jw.beforeValue()
+	jw.w.WriteByte(openBracket)
+	jw.stack = append(jw.stack, frame{array: true})
+}
+
+// EndArray closes a JSON array begun with BeginArray.
+func (jw *writer) EndArray() {
+	top := jw.top()
+	if !top.array {
+		panic("EndArray does not match BeginObject")
+	}
+	jw.stack = jw.stack[:len(jw.stack)-1]
+	jw.w.WriteByte(closeBracket)
+	jw.afterValue()
+}
+
+// Key writes a property name within an Object begun with BeginObject.  It
+// must be followed by exactly one value.
+func (jw *writer) Key(name string) {
+	top := jw.top()
+	if top.array {
+		panic("Key can only occur within an Object")
+	}
+	if !top.needKey {
+		panic("Key called where a value was expected")
+	}
+	if top.comma {
+		jw.w.WriteByte(comma)
+	}
+	jw.writeQuoted(name)
+	jw.w.WriteByte(colon)
+	top.comma = false
+	top.needKey = false
+}
+
+// Object writes an object to the JSON output.  The properties of the object are
+// given by Prop calls made in the supplied function.
+func (jw *writer) Object(f func()) {
+	jw.BeginObject()
+	f()
+	jw.EndObject()
 }
 
 // Prop writes a property to an object definition in the JSON output.  The value
 // may be either nil, a string, or a function that uses Writer calls to render
 // the value.
 func (jw *writer) Prop(name string, value interface{}) {
-	if !jw.inObject {
-		panic("Prop can only occur within Object")
-	}
-	jw.inObject = false
-	jw.String(name)
-	jw.w.WriteByte(colon)
-	jw.comma = false
+	jw.Key(name)
 	switch v := value.(type) {
 	case nil:
 		jw.Null()
@@ -106,100 +250,147 @@ func (jw *writer) Prop(name string, value interface{}) {
 		jw.String(v)
 	case int:
 		jw.Int(v)
+	case int64:
+		jw.Int64(v)
+	case uint64:
+		jw.Uint64(v)
 	case bool:
 		jw.Bool(v)
 	case float64:
 		jw.Float64(v)
+	case float32:
+		jw.Float32(v)
 	case func():
 		v()
-		if !jw.comma {
+		if !jw.top().comma {
 			panic("Prop value function did not write anything")
 		}
 	default:
 		panic("unknown Prop value type")
 	}
-	jw.comma = true
-	jw.inObject = true
 }
 
 // Array writes an array to the JSON output.  The contents of the array are
 // given by the Writer calls made in the supplied function.
 func (jw *writer) Array(f func()) {
-	if jw.inObject {
-		panic("Object can only contain Prop")
-	}
-	if jw.comma {
-		jw.w.WriteByte(comma)
-	}
-	jw.comma = false
-	jw.w.WriteByte(openBracket)
+	jw.BeginArray()
 	f()
-	jw.w.WriteByte(closeBracket)
-	jw.comma = true
+	jw.EndArray()
 }
 
 // Null writes a null to the JSON output.
 func (jw *writer) Null() {
-	if jw.inObject {
-		panic("Object can only contain Prop")
-	}
-	if jw.comma {
-		jw.w.WriteByte(comma)
-	}
-	jw.comma = true
+	jw.beforeValue()
 	jw.w.WriteString(null)
+	jw.afterValue()
 }
 
 // Int writes an integer to the JSON output.
 func (jw *writer) Int(i int) {
-	if jw.inObject {
-		panic("Object can only contain Prop")
-	}
-	if jw.comma {
-		jw.w.WriteByte(comma)
-	}
-	jw.comma = true
+	jw.beforeValue()
+	fmt.Fprintf(jw.w, "%d", i)
+	jw.afterValue()
+}
+
+// Int64 writes an int64 to the JSON output, avoiding the truncation risk of
+// Int on 32-bit platforms.
+func (jw *writer) Int64(i int64) {
+	jw.beforeValue()
 	fmt.Fprintf(jw.w, "%d", i)
+	jw.afterValue()
 }
 
-// Float64 writes a float64 to the JSON output.
+// Uint64 writes a uint64 to the JSON output.
+func (jw *writer) Uint64(u uint64) {
+	jw.beforeValue()
+	fmt.Fprintf(jw.w, "%d", u)
+	jw.afterValue()
+}
+
+// Number writes the untouched textual form of a number to the JSON output,
+// so that callers dealing with big.Int, big.Float, or other arbitrary
+// precision values aren't forced through a float64 or int64 conversion.
+// The caller is responsible for ensuring raw is a valid JSON number.
+func (jw *writer) Number(raw string) {
+	jw.beforeValue()
+	jw.w.WriteString(raw)
+	jw.afterValue()
+}
+
+// Float64 writes a float64 to the JSON output, using the shortest
+// representation that round-trips, matching encoding/json.  A NaN or ±Inf
+// value is handled according to the WriterOption(s), if any, passed to
+// NewWriter; by default, it causes a panic, since none of them are valid
+// JSON.
 func (jw *writer) Float64(f float64) {
-	if jw.inObject {
-		panic("Object can only contain Prop")
+	jw.beforeValue()
+	jw.writeFloat(f, 64)
+	jw.afterValue()
+}
+
+// Float32 writes a float32 to the JSON output; see Float64.
+func (jw *writer) Float32(f float32) {
+	jw.beforeValue()
+	jw.writeFloat(float64(f), 32)
+	jw.afterValue()
+}
+
+// writeFloat writes a float of the given bit size to the JSON output,
+// without any comma or protocol bookkeeping.
+func (jw *writer) writeFloat(f float64, bitSize int) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		switch jw.nonFinite {
+		case nonFiniteNull:
+			jw.w.WriteString(null)
+		case nonFiniteString:
+			jw.writeQuoted(jw.nonFiniteStr)
+		default:
+			panic("cannot encode non-finite float as JSON")
+		}
+		return
 	}
-	if jw.comma {
-		jw.w.WriteByte(comma)
+	fmtByte := byte('f')
+	if abs := math.Abs(f); abs != 0 {
+		if bitSize == 64 && (abs < 1e-6 || abs >= 1e21) {
+			fmtByte = 'e'
+		} else if bitSize == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			fmtByte = 'e'
+		}
 	}
-	jw.comma = true
-	fmt.Fprintf(jw.w, "%f", f)
+	var scratch [32]byte
+	b := strconv.AppendFloat(scratch[:0], f, fmtByte, -1, bitSize)
+	if fmtByte == 'e' {
+		// Go zero-pads single-digit negative exponents ("1e-09"); JSON
+		// (and encoding/json) doesn't ("1e-9").
+		if n := len(b); n >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+			b[n-2] = b[n-1]
+			b = b[:n-1]
+		}
+	}
+	jw.w.Write(b)
 }
 
 // Bool writes a boolean to the JSON output.
 func (jw *writer) Bool(b bool) {
-	if jw.inObject {
-		panic("Object can only contain Prop")
-	}
-	if jw.comma {
-		jw.w.WriteByte(comma)
-	}
-	jw.comma = true
+	jw.beforeValue()
 	if b {
 		jw.w.WriteString("true")
 	} else {
 		jw.w.WriteString("false")
 	}
+	jw.afterValue()
 }
 
 // Raw writes a string to the JSON output without encoding.
 func (jw *writer) Raw(s string) {
 	jw.w.WriteString(s)
-	jw.comma = false
+	jw.top().comma = false
 }
 
 // RawByte writes a byte to the JSON output without encoding.
 func (jw *writer) RawByte(b byte) {
 	jw.w.WriteByte(b)
-	jw.comma = false
+	jw.top().comma = false
 }
 
 // Everything from here on down was copied and modified from the code in the
@@ -207,13 +398,15 @@ func (jw *writer) RawByte(b byte) {
 
 // String writes a quoted string to the JSON output, with appropriate escaping.
 func (jw *writer) String(s string) {
-	if jw.inObject {
-		panic("Object can only contain Prop")
-	}
-	if jw.comma {
-		jw.w.WriteByte(comma)
-	}
-	jw.comma = true
+	jw.beforeValue()
+	jw.writeQuoted(s)
+	jw.afterValue()
+}
+
+// writeQuoted writes s to the JSON output as a quoted, escaped string,
+// without any comma or protocol bookkeeping.  It is used both for values
+// (String) and for object property names (Key).
+func (jw *writer) writeQuoted(s string) {
 	jw.w.WriteByte(dquote)
 	start := 0
 	for i := 0; i < len(s); {
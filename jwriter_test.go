@@ -0,0 +1,243 @@
+package json
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+// TestWriterStreamingRoundTrip verifies that a document built with the
+// streaming BeginObject/Key/EndObject/BeginArray/EndArray calls produces
+// valid JSON that Reader can parse back, including objects nested inside
+// arrays nested inside objects.
+func TestWriterStreamingRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	jw := NewWriter(&buf)
+	jw.BeginObject()
+	jw.Key("name")
+	jw.String("widget")
+	jw.Key("tags")
+	jw.BeginArray()
+	jw.String("a")
+	jw.String("b")
+	jw.EndArray()
+	jw.Key("meta")
+	jw.BeginObject()
+	jw.Key("count")
+	jw.Int(2)
+	jw.EndObject()
+	jw.EndObject()
+	jw.Close()
+
+	var name string
+	var tags []string
+	var count int
+	err := NewReader(strings.NewReader(buf.String())).Read(ObjectHandler(func(key string) Handlers {
+		switch key {
+		case "name":
+			return StringHandler(func(s string) { name = s })
+		case "tags":
+			return ArrayHandler(func() Handlers {
+				return StringHandler(func(s string) { tags = append(tags, s) })
+			})
+		case "meta":
+			return ObjectHandler(func(key string) Handlers {
+				if key == "count" {
+					return IntHandler(func(i int) { count = i })
+				}
+				return RejectHandler()
+			})
+		}
+		return RejectHandler()
+	}))
+	if err != nil {
+		t.Fatalf("Read returned error: %v", err)
+	}
+	if name != "widget" {
+		t.Fatalf("name = %q, want %q", name, "widget")
+	}
+	if len(tags) != 2 || tags[0] != "a" || tags[1] != "b" {
+		t.Fatalf("tags = %v, want [a b]", tags)
+	}
+	if count != 2 {
+		t.Fatalf("count = %d, want 2", count)
+	}
+}
+
+// TestWriterObjectArraySugar verifies that Object/Prop/Array, which are
+// implemented on top of the streaming calls, emit the same JSON that the
+// streaming calls would.
+func TestWriterObjectArraySugar(t *testing.T) {
+	var buf bytes.Buffer
+	jw := NewWriter(&buf)
+	jw.Object(func() {
+		jw.Prop("a", 1)
+		jw.Prop("b", nil)
+	})
+	jw.Close()
+
+	if got, want := buf.String(), `{"a":1,"b":null}`; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	var a int
+	var b *int
+	err := NewReader(strings.NewReader(buf.String())).Read(ObjectHandler(func(key string) Handlers {
+		switch key {
+		case "a":
+			return IntHandler(func(i int) { a = i })
+		case "b":
+			return NullHandler(func() { b = new(int) })
+		}
+		return RejectHandler()
+	}))
+	if err != nil {
+		t.Fatalf("Read returned error: %v", err)
+	}
+	if a != 1 || b == nil {
+		t.Fatalf("a = %d, b = %v, want a = 1, b non-nil", a, b)
+	}
+}
+
+// mustPanic calls f and fails the test unless it panics with the given
+// message.
+func mustPanic(t *testing.T, want string, f func()) {
+	t.Helper()
+	defer func() {
+		r := recover()
+		if r == nil {
+			t.Fatalf("expected panic %q, got none", want)
+		}
+		if r != want {
+			t.Fatalf("got panic %q, want %q", r, want)
+		}
+	}()
+	f()
+}
+
+// TestWriterPanics verifies that the streaming API panics, rather than
+// producing malformed JSON, when its calls are misused.
+func TestWriterPanics(t *testing.T) {
+	mustPanic(t, "EndObject does not match BeginArray", func() {
+		jw := NewWriter(&bytes.Buffer{})
+		jw.BeginArray()
+		jw.EndObject()
+	})
+	mustPanic(t, "EndArray does not match BeginObject", func() {
+		jw := NewWriter(&bytes.Buffer{})
+		jw.BeginObject()
+		jw.EndArray()
+	})
+	mustPanic(t, "Key can only occur within an Object", func() {
+		jw := NewWriter(&bytes.Buffer{})
+		jw.BeginArray()
+		jw.Key("a")
+	})
+	mustPanic(t, "Key called where a value was expected", func() {
+		jw := NewWriter(&bytes.Buffer{})
+		jw.BeginObject()
+		jw.Key("a")
+		jw.Key("b")
+	})
+	mustPanic(t, "expected a Key, not a value, in JSON object", func() {
+		jw := NewWriter(&bytes.Buffer{})
+		jw.BeginObject()
+		jw.Int(1)
+	})
+	mustPanic(t, "EndObject called with a Key but no value", func() {
+		jw := NewWriter(&bytes.Buffer{})
+		jw.BeginObject()
+		jw.Key("a")
+		jw.EndObject()
+	})
+}
+
+// TestWriterFloat verifies that Float64/Float32 use the shortest
+// round-tripping representation, matching encoding/json's choice of 'f' or
+// 'e' notation and its exponent normalization, rather than the garbage
+// %f once produced for large magnitudes.
+func TestWriterFloat(t *testing.T) {
+	tests := []struct {
+		f    float64
+		want string
+	}{
+		{0, "0"},
+		{1e21, "1e+21"},
+		{1e-7, "1e-7"},
+		{-1.5, "-1.5"},
+		{100000000000000000000, "100000000000000000000"},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		jw := NewWriter(&buf)
+		jw.Float64(test.f)
+		jw.Close()
+		if got := buf.String(); got != test.want {
+			t.Errorf("Float64(%v) = %q, want %q", test.f, got, test.want)
+		}
+	}
+
+	var buf bytes.Buffer
+	jw := NewWriter(&buf)
+	jw.Float32(1.5)
+	jw.Close()
+	if got, want := buf.String(), "1.5"; got != want {
+		t.Errorf("Float32(1.5) = %q, want %q", got, want)
+	}
+}
+
+// TestWriterNonFinite verifies that a NaN or ±Inf float panics by default,
+// and is instead encoded as null or as a given string when the matching
+// WriterOption is given to NewWriter.
+func TestWriterNonFinite(t *testing.T) {
+	mustPanic(t, "cannot encode non-finite float as JSON", func() {
+		jw := NewWriter(&bytes.Buffer{})
+		jw.Float64(math.NaN())
+	})
+
+	var buf bytes.Buffer
+	jw := NewWriter(&buf, WithNonFiniteNull())
+	jw.Float64(math.Inf(1))
+	jw.Close()
+	if got, want := buf.String(), "null"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	jw = NewWriter(&buf, WithNonFiniteString("NaN"))
+	jw.Float64(math.NaN())
+	jw.Close()
+	if got, want := buf.String(), `"NaN"`; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// TestWriterInt64Uint64Number verifies that Int64, Uint64, and Number write
+// their values without the truncation or reformatting that Int or a
+// float64 conversion would risk.
+func TestWriterInt64Uint64Number(t *testing.T) {
+	var buf bytes.Buffer
+	jw := NewWriter(&buf)
+	jw.Int64(-9223372036854775808)
+	jw.Close()
+	if got, want := buf.String(), "-9223372036854775808"; got != want {
+		t.Errorf("Int64 = %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	jw = NewWriter(&buf)
+	jw.Uint64(18446744073709551615)
+	jw.Close()
+	if got, want := buf.String(), "18446744073709551615"; got != want {
+		t.Errorf("Uint64 = %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	jw = NewWriter(&buf)
+	jw.Number("12345678901234567890.5")
+	jw.Close()
+	if got, want := buf.String(), "12345678901234567890.5"; got != want {
+		t.Errorf("Number = %q, want %q", got, want)
+	}
+}